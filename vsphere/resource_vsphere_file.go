@@ -0,0 +1,284 @@
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+)
+
+func resourceVSphereFile() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceVSphereFileCreate,
+		Read:   resourceVSphereFileRead,
+		Update: resourceVSphereFileUpdate,
+		Delete: resourceVSphereFileDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereFileImport,
+		},
+		Schema: map[string]*schema.Schema{
+			"datacenter": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of a datacenter in which the file will be uploaded.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"datastore": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of the datastore to which the file will be uploaded.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"source_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The path to the file being uploaded from Terraform host, or copied from source_datastore.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"source_datacenter": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of a datacenter in which the file will be copied from.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"source_datastore": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of the datastore from which file will be copied.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"destination_file": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The path to where the file should be uploaded or copied to on the destination datastore.",
+				Required:    true,
+			},
+			"create_directories": &schema.Schema{
+				Type:        schema.TypeBool,
+				Description: "Create directories in destination_file path parameter if any missing for upload or copy operation.",
+				Optional:    true,
+				Default:     false,
+			},
+		},
+	}
+}
+
+func resourceVSphereFileCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+	df := d.Get("destination_file").(string)
+
+	if d.Get("create_directories").(bool) {
+		fm := object.NewFileManager(client.Client)
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		if err := fm.MakeDirectory(ctx, ds.Path(path.Dir(df)), dc, true); err != nil {
+			return fmt.Errorf("error creating parent directories for %q: %s", df, err)
+		}
+	}
+
+	sf := d.Get("source_file").(string)
+	sds := d.Get("source_datastore").(string)
+	switch {
+	case sds != "":
+		// Copy across datastores.
+		sdc, err := datacenterFromName(client, d.Get("source_datacenter").(string))
+		if err != nil {
+			return fmt.Errorf("error loading source datacenter: %s", err)
+		}
+		sourceDS, err := datastoreFromNameInDatacenter(client, sdc, sds)
+		if err != nil {
+			return fmt.Errorf("error loading source datastore: %s", err)
+		}
+		fm := object.NewFileManager(client.Client)
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		task, err := fm.CopyDatastoreFile(ctx, sourceDS.Path(sf), sdc, ds.Path(df), dc, true)
+		if err != nil {
+			return fmt.Errorf("error copying file from %q to %q: %s", sf, df, err)
+		}
+		if err := task.Wait(ctx); err != nil {
+			return fmt.Errorf("error waiting for copy of %q to %q: %s", sf, df, err)
+		}
+	case sf != "":
+		// Upload from the Terraform host.
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		p := soap.DefaultUpload
+		if err := ds.UploadFile(ctx, sf, df, &p); err != nil {
+			return fmt.Errorf("error uploading file %q to %q: %s", sf, df, err)
+		}
+	default:
+		return fmt.Errorf("one of source_file or source_datastore must be specified")
+	}
+
+	d.SetId(fileID(d.Get("datacenter").(string), ds.Name(), df))
+
+	return resourceVSphereFileRead(d, meta)
+}
+
+func resourceVSphereFileRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+	df := d.Get("destination_file").(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if _, err := ds.Stat(ctx, df); err != nil {
+		if isManagedObjectNotFoundError(err) || isFileNotFoundError(err) {
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("error checking for file %q: %s", df, err)
+	}
+
+	return nil
+}
+
+func resourceVSphereFileUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+
+	if d.HasChange("destination_file") {
+		dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+		if err != nil {
+			return fmt.Errorf("error loading datacenter: %s", err)
+		}
+		ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+		if err != nil {
+			return fmt.Errorf("error loading datastore: %s", err)
+		}
+		old, new := d.GetChange("destination_file")
+		fm := object.NewFileManager(client.Client)
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		task, err := fm.MoveDatastoreFile(ctx, ds.Path(old.(string)), dc, ds.Path(new.(string)), dc, true)
+		if err != nil {
+			return fmt.Errorf("error moving file from %q to %q: %s", old, new, err)
+		}
+		if err := task.Wait(ctx); err != nil {
+			return fmt.Errorf("error waiting for move of %q to %q: %s", old, new, err)
+		}
+		d.SetId(fileID(d.Get("datacenter").(string), ds.Name(), new.(string)))
+	}
+
+	return resourceVSphereFileRead(d, meta)
+}
+
+func resourceVSphereFileDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+	df := d.Get("destination_file").(string)
+
+	fm := object.NewFileManager(client.Client)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	task, err := fm.DeleteDatastoreFile(ctx, ds.Path(df), dc)
+	if err != nil {
+		return fmt.Errorf("error deleting file %q: %s", df, err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("error waiting for deletion of %q: %s", df, err)
+	}
+
+	return nil
+}
+
+func resourceVSphereFileImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// The ID is of the form DATACENTER:DATASTORE:PATH, matching the import
+	// format used by vsphere_virtual_disk, so that files living outside of
+	// the default datacenter can be imported.
+	ids := strings.SplitN(d.Id(), ":", 3)
+	if len(ids) != 3 {
+		return nil, fmt.Errorf("please supply the ID in the following format: DATACENTER:DATASTORE:PATH")
+	}
+	dc := ids[0]
+	ds := ids[1]
+	df := ids[2]
+
+	client := meta.(*VSphereClient).vimClient
+	dcObj, err := datacenterFromName(client, dc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading datacenter: %s", err)
+	}
+	dsObj, err := datastoreFromNameInDatacenter(client, dcObj, ds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find datastore: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if _, err := dsObj.Stat(ctx, df); err != nil {
+		return nil, fmt.Errorf("cannot find file: %s", err)
+	}
+
+	d.Set("datacenter", dc)
+	d.Set("datastore", ds)
+	d.Set("destination_file", df)
+	d.SetId(fileID(dc, ds, df))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// fileID builds the resource ID for a vsphere_file resource, in the form
+// DATACENTER:DATASTORE:PATH.
+func fileID(dc, ds, df string) string {
+	return strings.Join([]string{dc, ds, df}, ":")
+}
+
+// datacenterFromName locates a datacenter by name. An empty name resolves to
+// the client's default datacenter.
+func datacenterFromName(client *govmomi.Client, name string) (*object.Datacenter, error) {
+	finder := find.NewFinder(client.Client, false)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if name == "" {
+		return finder.DefaultDatacenter(ctx)
+	}
+	return finder.Datacenter(ctx, name)
+}
+
+// datastoreFromNameInDatacenter locates a datastore by name, scoped to dc.
+func datastoreFromNameInDatacenter(client *govmomi.Client, dc *object.Datacenter, name string) (*object.Datastore, error) {
+	finder := find.NewFinder(client.Client, false)
+	finder.SetDatacenter(dc)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	return finder.Datastore(ctx, name)
+}
+
+// isFileNotFoundError returns true if err indicates that a file or directory
+// could not be found on a datastore, as surfaced by Datastore.Stat and the
+// FileManager task methods.
+func isFileNotFoundError(err error) bool {
+	switch err.(type) {
+	case object.DatastoreNoSuchFileError, object.DatastoreNoSuchDirectoryError:
+		return true
+	default:
+		return false
+	}
+}
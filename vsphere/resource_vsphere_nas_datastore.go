@@ -0,0 +1,399 @@
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func resourceVSphereNasDatastore() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"name": &schema.Schema{
+			Type:        schema.TypeString,
+			Description: "The name of the datastore.",
+			Required:    true,
+		},
+		"host_system_ids": &schema.Schema{
+			Type:        schema.TypeList,
+			Description: "The managed object IDs of the hosts to mount the datastore on.",
+			Required:    true,
+			MinItems:    1,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"folder": &schema.Schema{
+			Type:        schema.TypeString,
+			Description: "The path to the datastore folder to put the datastore in.",
+			Optional:    true,
+			StateFunc:   normalizeFolderPath,
+		},
+		"type": &schema.Schema{
+			Type:         schema.TypeString,
+			Description:  "The type of NAS volume. Can be one of NFS (to denote v3) or NFS41 (to denote NFS v4.1).",
+			Optional:     true,
+			ForceNew:     true,
+			Default:      "NFS",
+			ValidateFunc: validation.StringInSlice([]string{"NFS", "NFS41"}, false),
+		},
+		"remote_hosts": &schema.Schema{
+			Type:        schema.TypeList,
+			Description: "The hostnames or IP addresses of the remote server or servers exporting the NFS share.",
+			Required:    true,
+			ForceNew:    true,
+			MinItems:    1,
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"remote_path": &schema.Schema{
+			Type:        schema.TypeString,
+			Description: "The remote path of the mount point.",
+			Required:    true,
+			ForceNew:    true,
+		},
+		"access_mode": &schema.Schema{
+			Type:         schema.TypeString,
+			Description:  "Access mode for the mount point. Can be one of readOnly or readWrite.",
+			Optional:     true,
+			ForceNew:     true,
+			Default:      "readWrite",
+			ValidateFunc: validation.StringInSlice([]string{"readOnly", "readWrite"}, false),
+		},
+		"security_type": &schema.Schema{
+			Type:         schema.TypeString,
+			Description:  "Security type for NFS41 mount point. Can be one of AUTH_SYS, SEC_KRB5, or SEC_KRB5I.",
+			Optional:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringInSlice([]string{"AUTH_SYS", "SEC_KRB5", "SEC_KRB5I"}, false),
+		},
+	}
+	mergeSchema(s, schemaDatastoreSummary())
+	return &schema.Resource{
+		Create: resourceVSphereNasDatastoreCreate,
+		Read:   resourceVSphereNasDatastoreRead,
+		Update: resourceVSphereNasDatastoreUpdate,
+		Delete: resourceVSphereNasDatastoreDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereNasDatastoreImport,
+		},
+		Schema: s,
+	}
+}
+
+func resourceVSphereNasDatastoreCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	hsIDs := sliceInterfacesToStrings(d.Get("host_system_ids").([]interface{}))
+
+	spec := nasVolumeSpecForCreate(d)
+
+	// Mount the datastore on the first host, which creates the datastore
+	// object. We record the ID the moment that succeeds, since a single
+	// datastore is typically mounted on many hosts, and a failure mounting a
+	// later host shouldn't leave an already-created datastore untracked and
+	// orphaned - with the ID set, a subsequent apply will go through Update
+	// and simply mount the hosts that are still missing.
+	dss, err := hostDatastoreSystemFromHostSystemID(client, hsIDs[0])
+	if err != nil {
+		return fmt.Errorf("error loading host datastore system for host %q: %s", hsIDs[0], err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	ds, err := dss.CreateNasDatastore(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("error mounting NAS datastore on host %q: %s", hsIDs[0], err)
+	}
+	d.SetId(ds.Reference().Value)
+
+	for _, hsID := range hsIDs[1:] {
+		dss, err := hostDatastoreSystemFromHostSystemID(client, hsID)
+		if err != nil {
+			return fmt.Errorf("error loading host datastore system for host %q: %s", hsID, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		if _, err := dss.CreateNasDatastore(ctx, spec); err != nil {
+			return fmt.Errorf("error mounting NAS datastore on host %q (datastore %q is already mounted on prior hosts - re-apply to mount remaining hosts): %s", hsID, d.Id(), err)
+		}
+	}
+
+	// Move the datastore to the correct folder, if specified.
+	folder := d.Get("folder").(string)
+	if !pathIsEmpty(folder) {
+		if err := moveDatastoreToFolderRelativeHostSystemID(client, ds, hsIDs[0], folder); err != nil {
+			return fmt.Errorf("could not move datastore to folder %q: %s", folder, err)
+		}
+	}
+
+	return resourceVSphereNasDatastoreRead(d, meta)
+}
+
+func resourceVSphereNasDatastoreRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+	ds, err := datastoreFromID(client, id)
+	if err != nil {
+		return fmt.Errorf("cannot find datastore: %s", err)
+	}
+	props, err := datastoreProperties(ds)
+	if err != nil {
+		return fmt.Errorf("could not get properties for datastore: %s", err)
+	}
+	if err := flattenDatastoreSummary(d, &props.Summary); err != nil {
+		return err
+	}
+
+	// Set the folder
+	folder, err := rootPathParticleDatastore.SplitRelativeFolder(ds.InventoryPath)
+	if err != nil {
+		return fmt.Errorf("error parsing datastore path %q: %s", ds.InventoryPath, err)
+	}
+	d.Set("folder", normalizeFolderPath(folder))
+
+	info := props.Info.(*types.NasDatastoreInfo)
+	d.Set("remote_path", info.Nas.RemotePath)
+	remoteHosts := info.Nas.RemoteHostNames
+	if len(remoteHosts) < 1 {
+		// Older hosts/datastores that were only ever mounted against a single
+		// NFS server may not populate RemoteHostNames - fall back to the
+		// singular, deprecated RemoteHost field in that case.
+		remoteHosts = []string{info.Nas.RemoteHost}
+	}
+	d.Set("remote_hosts", remoteHosts)
+	d.Set("type", info.Nas.Type)
+	d.Set("access_mode", info.Nas.Mode)
+	d.Set("security_type", info.Nas.Security)
+
+	var hsIDs []string
+	for _, mount := range props.Host {
+		hsIDs = append(hsIDs, mount.Key.Value)
+	}
+	if err := d.Set("host_system_ids", hsIDs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func resourceVSphereNasDatastoreUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+	ds, err := datastoreFromID(client, id)
+	if err != nil {
+		return fmt.Errorf("cannot find datastore: %s", err)
+	}
+
+	// Rename this datastore if our name has drifted.
+	if d.HasChange("name") {
+		if err := renameObject(client, ds.Reference(), d.Get("name").(string)); err != nil {
+			return err
+		}
+	}
+
+	// Update folder if necessary
+	if d.HasChange("folder") {
+		folder := d.Get("folder").(string)
+		if err := moveDatastoreToFolder(client, ds, folder); err != nil {
+			return fmt.Errorf("could not move datastore to folder %q: %s", folder, err)
+		}
+	}
+
+	// Diff the set of mounted hosts, mounting the datastore on any host that
+	// has been added, and unmounting it from any host that has been removed.
+	old, new := d.GetChange("host_system_ids")
+	oldIDs := sliceInterfacesToStrings(old.([]interface{}))
+	newIDs := sliceInterfacesToStrings(new.([]interface{}))
+
+	spec := nasVolumeSpecForCreate(d)
+	for _, hsID := range newIDs {
+		if sliceStringsContains(oldIDs, hsID) {
+			continue
+		}
+		dss, err := hostDatastoreSystemFromHostSystemID(client, hsID)
+		if err != nil {
+			return fmt.Errorf("error loading host datastore system for host %q: %s", hsID, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		if _, err := dss.CreateNasDatastore(ctx, spec); err != nil {
+			return fmt.Errorf("error mounting NAS datastore on host %q: %s", hsID, err)
+		}
+	}
+
+	for _, hsID := range oldIDs {
+		if sliceStringsContains(newIDs, hsID) {
+			continue
+		}
+		dss, err := hostDatastoreSystemFromHostSystemID(client, hsID)
+		if err != nil {
+			return fmt.Errorf("error loading host datastore system for host %q: %s", hsID, err)
+		}
+		if err := removeDatastore(dss, ds); err != nil {
+			return fmt.Errorf("error unmounting NAS datastore from host %q: %s", hsID, err)
+		}
+	}
+
+	return resourceVSphereNasDatastoreRead(d, meta)
+}
+
+func resourceVSphereNasDatastoreDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+	ds, err := datastoreFromID(client, id)
+	if err != nil {
+		return fmt.Errorf("cannot find datastore: %s", err)
+	}
+
+	hsIDs := sliceInterfacesToStrings(d.Get("host_system_ids").([]interface{}))
+	for _, hsID := range hsIDs {
+		dss, err := hostDatastoreSystemFromHostSystemID(client, hsID)
+		if err != nil {
+			return fmt.Errorf("error loading host datastore system for host %q: %s", hsID, err)
+		}
+
+		// Use the same short retry state machine as the VMFS datastore resource
+		// to smooth over the same in-use race when unmounting from many hosts.
+		deleteRetryFunc := func() (interface{}, string, error) {
+			err := removeDatastore(dss, ds)
+			if err != nil {
+				if isResourceInUseError(err) {
+					// Pending
+					return struct{}{}, retryDeletePending, nil
+				}
+				// Some other error
+				return struct{}{}, retryDeleteError, err
+			}
+			// Done
+			return struct{}{}, retryDeleteCompleted, nil
+		}
+
+		deleteRetry := &resource.StateChangeConf{
+			Pending:    []string{retryDeletePending},
+			Target:     []string{retryDeleteCompleted},
+			Refresh:    deleteRetryFunc,
+			Timeout:    30 * time.Second,
+			MinTimeout: 2 * time.Second,
+			Delay:      2 * time.Second,
+		}
+
+		if _, err := deleteRetry.WaitForState(); err != nil {
+			return fmt.Errorf("could not unmount datastore from host %q: %s", hsID, err)
+		}
+	}
+
+	// We need to make sure the datastore is completely removed. There appears to
+	// be a bit of a delay sometimes on vCenter, and it causes issues in tests,
+	// which means it could cause issues somewhere else too.
+	waitForDeleteFunc := func() (interface{}, string, error) {
+		_, err := datastoreFromID(client, id)
+		if err != nil {
+			if isManagedObjectNotFoundError(err) {
+				// Done
+				return struct{}{}, waitForDeleteCompleted, nil
+			}
+			// Some other error
+			return struct{}{}, waitForDeleteError, err
+		}
+		return struct{}{}, waitForDeletePending, nil
+	}
+
+	waitForDelete := &resource.StateChangeConf{
+		Pending:        []string{waitForDeletePending},
+		Target:         []string{waitForDeleteCompleted},
+		Refresh:        waitForDeleteFunc,
+		Timeout:        defaultAPITimeout,
+		MinTimeout:     2 * time.Second,
+		Delay:          1 * time.Second,
+		NotFoundChecks: 35,
+	}
+
+	if _, err := waitForDelete.WaitForState(); err != nil {
+		return fmt.Errorf("error waiting for datastore to delete: %s", err.Error())
+	}
+
+	return nil
+}
+
+func resourceVSphereNasDatastoreImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// We support importing a MoRef - so we need to load the datastore and check
+	// to make sure 1) it exists, and 2) it's an NFS datastore. If it is, we are
+	// good to go (rest of the stuff will be handled by read on refresh).
+	ids := strings.SplitN(d.Id(), ":", 2)
+	if len(ids) != 2 {
+		return nil, errors.New("please supply the ID in the following format: DATASTOREID:HOSTID")
+	}
+
+	id := ids[0]
+	hsID := ids[1]
+	client := meta.(*VSphereClient).vimClient
+	ds, err := datastoreFromID(client, id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find datastore: %s", err)
+	}
+	props, err := datastoreProperties(ds)
+	if err != nil {
+		return nil, fmt.Errorf("could not get properties for datastore: %s", err)
+	}
+
+	switch types.HostFileSystemVolumeFileSystemType(props.Summary.Type) {
+	case types.HostFileSystemVolumeFileSystemTypeNFS, types.HostFileSystemVolumeFileSystemTypeNFS41:
+	default:
+		return nil, fmt.Errorf("datastore ID %q is not an NFS or NFS41 datastore", id)
+	}
+
+	var found bool
+	for _, mount := range props.Host {
+		if mount.Key.Value == hsID {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("configured host_system_id %q not found as a mounted host on datastore", hsID)
+	}
+
+	nasInfo := props.Info.(*types.NasDatastoreInfo)
+	d.Set("type", nasInfo.Nas.Type)
+	d.Set("access_mode", nasInfo.Nas.Mode)
+	d.SetId(id)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// nasVolumeSpecForCreate builds the HostNasVolumeSpec used to mount the NAS
+// datastore, shared between resourceVSphereNasDatastoreCreate and
+// resourceVSphereNasDatastoreUpdate.
+func nasVolumeSpecForCreate(d *schema.ResourceData) types.HostNasVolumeSpec {
+	remoteHosts := sliceInterfacesToStrings(d.Get("remote_hosts").([]interface{}))
+	return types.HostNasVolumeSpec{
+		RemoteHost:   remoteHosts[0],
+		RemoteHosts:  remoteHosts,
+		RemotePath:   d.Get("remote_path").(string),
+		LocalPath:    d.Get("name").(string),
+		AccessMode:   d.Get("access_mode").(string),
+		Type:         d.Get("type").(string),
+		SecurityType: d.Get("security_type").(string),
+	}
+}
+
+// sliceInterfacesToStrings converts a []interface{} as returned by
+// schema.ResourceData for a TypeList of strings into a []string.
+func sliceInterfacesToStrings(in []interface{}) []string {
+	out := make([]string, len(in))
+	for i, v := range in {
+		out[i] = v.(string)
+	}
+	return out
+}
+
+// sliceStringsContains returns true if s is present in in.
+func sliceStringsContains(in []string, s string) bool {
+	for _, v := range in {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
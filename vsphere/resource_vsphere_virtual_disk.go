@@ -0,0 +1,215 @@
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var virtualDiskDiskTypeAllowedTypes = []string{
+	"thin",
+	"eagerZeroedThick",
+	"preallocated",
+}
+
+var virtualDiskAdapterTypeAllowedTypes = []string{
+	"ide",
+	"busLogic",
+	"lsiLogic",
+}
+
+func resourceVSphereVirtualDisk() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceVSphereVirtualDiskCreate,
+		Read:   resourceVSphereVirtualDiskRead,
+		Update: resourceVSphereVirtualDiskUpdate,
+		Delete: resourceVSphereVirtualDiskDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereVirtualDiskImport,
+		},
+		Schema: map[string]*schema.Schema{
+			"size": &schema.Schema{
+				Type:        schema.TypeInt,
+				Description: "The size of the disk, in GB.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"vmdk_path": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The path, including filename, of the virtual disk to be created. This needs to end in '.vmdk'.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"datacenter": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of a datacenter in which the disk will be created.",
+				Optional:    true,
+				ForceNew:    true,
+			},
+			"datastore": &schema.Schema{
+				Type:        schema.TypeString,
+				Description: "The name of the datastore on which the disk will be created.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"type": &schema.Schema{
+				Type:         schema.TypeString,
+				Description:  "The type of disk to create. Can be 'eagerZeroedThick', 'preallocated', or 'thin'.",
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "eagerZeroedThick",
+				ValidateFunc: validation.StringInSlice(virtualDiskDiskTypeAllowedTypes, false),
+			},
+			"adapter_type": &schema.Schema{
+				Type:         schema.TypeString,
+				Description:  "The adapter type for this virtual disk. Can be one of 'ide', 'busLogic', or 'lsiLogic'.",
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "lsiLogic",
+				ValidateFunc: validation.StringInSlice(virtualDiskAdapterTypeAllowedTypes, false),
+			},
+		},
+	}
+}
+
+func resourceVSphereVirtualDiskCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	dcName := d.Get("datacenter").(string)
+	dsName := d.Get("datastore").(string)
+	vmdkPath := d.Get("vmdk_path").(string)
+
+	dc, err := datacenterFromName(client, dcName)
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, dsName)
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+
+	vdm := object.NewVirtualDiskManager(client.Client)
+
+	spec := &types.FileBackedVirtualDiskSpec{
+		VirtualDiskSpec: types.VirtualDiskSpec{
+			AdapterType: d.Get("adapter_type").(string),
+			DiskType:    d.Get("type").(string),
+		},
+		CapacityKb: int64(d.Get("size").(int)) * 1024 * 1024,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	task, err := vdm.CreateVirtualDisk(ctx, ds.Path(vmdkPath), dc, spec)
+	if err != nil {
+		return fmt.Errorf("error creating virtual disk %q: %s", vmdkPath, err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("error waiting for virtual disk %q to be created: %s", vmdkPath, err)
+	}
+
+	d.SetId(virtualDiskID(dcName, dsName, vmdkPath))
+
+	return resourceVSphereVirtualDiskRead(d, meta)
+}
+
+func resourceVSphereVirtualDiskRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+	vmdkPath := d.Get("vmdk_path").(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if _, err := ds.Stat(ctx, vmdkPath); err != nil {
+		if isManagedObjectNotFoundError(err) || isFileNotFoundError(err) {
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("error checking for virtual disk %q: %s", vmdkPath, err)
+	}
+
+	return nil
+}
+
+func resourceVSphereVirtualDiskUpdate(d *schema.ResourceData, meta interface{}) error {
+	// Every attribute is ForceNew in v1, so there is nothing to do here yet.
+	// This is a placeholder for a future version that supports extending or
+	// renaming an existing virtual disk.
+	return nil
+}
+
+func resourceVSphereVirtualDiskDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	vmdkPath := d.Get("vmdk_path").(string)
+
+	dc, err := datacenterFromName(client, d.Get("datacenter").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datacenter: %s", err)
+	}
+	ds, err := datastoreFromNameInDatacenter(client, dc, d.Get("datastore").(string))
+	if err != nil {
+		return fmt.Errorf("error loading datastore: %s", err)
+	}
+
+	vdm := object.NewVirtualDiskManager(client.Client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	task, err := vdm.DeleteVirtualDisk(ctx, ds.Path(vmdkPath), dc)
+	if err != nil {
+		return fmt.Errorf("error deleting virtual disk %q: %s", vmdkPath, err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("error waiting for virtual disk %q to be deleted: %s", vmdkPath, err)
+	}
+
+	return nil
+}
+
+func resourceVSphereVirtualDiskImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// The ID is of the form DATACENTER:DATASTORE:VMDK_PATH.
+	ids := strings.SplitN(d.Id(), ":", 3)
+	if len(ids) != 3 {
+		return nil, fmt.Errorf("please supply the ID in the following format: DATACENTER:DATASTORE:VMDK_PATH")
+	}
+	dc := ids[0]
+	ds := ids[1]
+	vmdkPath := ids[2]
+
+	client := meta.(*VSphereClient).vimClient
+	dcObj, err := datacenterFromName(client, dc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading datacenter: %s", err)
+	}
+	dsObj, err := datastoreFromNameInDatacenter(client, dcObj, ds)
+	if err != nil {
+		return nil, fmt.Errorf("error loading datastore: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if _, err := dsObj.Stat(ctx, vmdkPath); err != nil {
+		return nil, fmt.Errorf("cannot find virtual disk: %s", err)
+	}
+
+	d.Set("datacenter", dc)
+	d.Set("datastore", ds)
+	d.Set("vmdk_path", vmdkPath)
+	d.SetId(virtualDiskID(dc, ds, vmdkPath))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func virtualDiskID(dc, ds, vmdkPath string) string {
+	return strings.Join([]string{dc, ds, vmdkPath}, ":")
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -55,6 +60,23 @@ Additionally, there was an error removing the created datastore:
 You will need to remove this datastore manually before trying again.
 `
 
+// vmfsDatastoreDiskSchema is the per-extent schema for the "disks" set. It's
+// shared with the v0 -> v1 state migration so both sides of the migration
+// agree on how a disk entry hashes.
+var vmfsDatastoreDiskSchema = map[string]*schema.Schema{
+	"name": &schema.Schema{
+		Type:        schema.TypeString,
+		Description: "The canonical name of the disk/LUN being used as an extent for the datastore.",
+		Required:    true,
+	},
+	"keep_on_remove": &schema.Schema{
+		Type:        schema.TypeBool,
+		Description: "Set to true to keep the extent's VMFS partition table intact when it's removed from the datastore config. Useful for extents living on shared SAN LUNs.",
+		Optional:    true,
+		Default:     false,
+	},
+}
+
 func resourceVSphereVmfsDatastore() *schema.Resource {
 	s := map[string]*schema.Schema{
 		"name": &schema.Schema{
@@ -75,11 +97,13 @@ func resourceVSphereVmfsDatastore() *schema.Resource {
 			StateFunc:   normalizeFolderPath,
 		},
 		"disks": &schema.Schema{
-			Type:        schema.TypeList,
+			Type:        schema.TypeSet,
 			Description: "The disks to add to the datastore.",
 			Required:    true,
 			MinItems:    1,
-			Elem:        &schema.Schema{Type: schema.TypeString},
+			Elem: &schema.Resource{
+				Schema: vmfsDatastoreDiskSchema,
+			},
 		},
 	}
 	mergeSchema(s, schemaDatastoreSummary())
@@ -91,7 +115,9 @@ func resourceVSphereVmfsDatastore() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: resourceVSphereVmfsDatastoreImport,
 		},
-		Schema: s,
+		SchemaVersion: 1,
+		MigrateState:  resourceVSphereVmfsDatastoreMigrateState,
+		Schema:        s,
 	}
 }
 
@@ -106,8 +132,8 @@ func resourceVSphereVmfsDatastoreCreate(d *schema.ResourceData, meta interface{}
 	// To ensure the datastore is fully created with all the disks that we want
 	// to add to it, first we add the initial disk, then we expand the disk with
 	// the rest of the extents.
-	disks := d.Get("disks").([]interface{})
-	disk := disks[0].(string)
+	disks := d.Get("disks").(*schema.Set).List()
+	disk := disks[0].(map[string]interface{})["name"].(string)
 	spec, err := diskSpecForCreate(dss, disk)
 	if err != nil {
 		return err
@@ -136,16 +162,17 @@ func resourceVSphereVmfsDatastoreCreate(d *schema.ResourceData, meta interface{}
 
 	// Now add any remaining disks.
 	for _, disk := range disks[1:] {
-		spec, err := diskSpecForExtend(dss, ds, disk.(string))
+		diskName := disk.(map[string]interface{})["name"].(string)
+		spec, err := diskSpecForExtend(dss, ds, diskName)
 		if err != nil {
 			// We have to destroy the created datastore here.
 			if remErr := removeDatastore(dss, ds); remErr != nil {
 				// We could not destroy the created datastore and there is now a dangling
 				// resource. We need to instruct the user to remove the datastore
 				// manually.
-				return fmt.Errorf(formatVmfsDatastoreCreateRollbackErrorUpdate, disk, err, remErr)
+				return fmt.Errorf(formatVmfsDatastoreCreateRollbackErrorUpdate, diskName, err, remErr)
 			}
-			return fmt.Errorf("error fetching datastore extend spec for disk %q: %s", disk, err)
+			return fmt.Errorf("error fetching datastore extend spec for disk %q: %s", diskName, err)
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
 		defer cancel()
@@ -154,9 +181,9 @@ func resourceVSphereVmfsDatastoreCreate(d *schema.ResourceData, meta interface{}
 				// We could not destroy the created datastore and there is now a dangling
 				// resource. We need to instruct the user to remove the datastore
 				// manually.
-				return fmt.Errorf(formatVmfsDatastoreCreateRollbackErrorUpdate, disk, err, remErr)
+				return fmt.Errorf(formatVmfsDatastoreCreateRollbackErrorUpdate, diskName, err, remErr)
 			}
-			return fmt.Errorf("error extending datastore with disk %q: %s", disk, err)
+			return fmt.Errorf("error extending datastore with disk %q: %s", diskName, err)
 		}
 	}
 
@@ -188,10 +215,20 @@ func resourceVSphereVmfsDatastoreRead(d *schema.ResourceData, meta interface{})
 	}
 	d.Set("folder", normalizeFolderPath(folder))
 
-	// We also need to update the disk list from the summary.
-	var disks []string
-	for _, disk := range props.Info.(*types.VmfsDatastoreInfo).Vmfs.Extent {
-		disks = append(disks, disk.DiskName)
+	// We also need to update the disk list from the summary. keep_on_remove is
+	// not tracked by vSphere, so we carry forward whatever is already in state
+	// for extents that are still present, and default new ones to false.
+	keepOnRemove := make(map[string]bool)
+	for _, v := range d.Get("disks").(*schema.Set).List() {
+		m := v.(map[string]interface{})
+		keepOnRemove[m["name"].(string)] = m["keep_on_remove"].(bool)
+	}
+	var disks []interface{}
+	for _, extent := range props.Info.(*types.VmfsDatastoreInfo).Vmfs.Extent {
+		disks = append(disks, map[string]interface{}{
+			"name":           extent.DiskName,
+			"keep_on_remove": keepOnRemove[extent.DiskName],
+		})
 	}
 	if err := d.Set("disks", disks); err != nil {
 		return err
@@ -229,41 +266,57 @@ func resourceVSphereVmfsDatastoreUpdate(d *schema.ResourceData, meta interface{}
 		}
 	}
 
-	// Veto this update if it means a disk was removed. Shrinking
-	// datastores/removing extents is not supported.
 	old, new := d.GetChange("disks")
-	for _, v1 := range old.([]interface{}) {
-		var found bool
-		for _, v2 := range new.([]interface{}) {
-			if v1.(string) == v2.(string) {
-				found = true
-			}
+	oldDisks := old.(*schema.Set)
+	newDisks := new.(*schema.Set)
+
+	// Add any new extents that have shown up in config first. This ensures
+	// that a single-apply swap of a datastore's sole extent (remove disk A,
+	// add disk B) never leaves ds pointing at a datastore that was just
+	// unmounted/wiped out from under it - extending onto the still-live
+	// datastore always has somewhere to land. Only once that's done do we
+	// process removals, which by then either succeed (the datastore has a
+	// single extent, so it's detached/wiped as a whole) or fail with the
+	// purpose-built errors from removeVmfsExtent instead of a confusing
+	// ManagedObjectNotFound further down the line.
+	for _, v := range newDisks.List() {
+		m := v.(map[string]interface{})
+		name := m["name"].(string)
+		if stillReferencedByName(oldDisks, name) {
+			continue
 		}
-		if !found {
-			return fmt.Errorf("disk %s found in state but not config (removal of disks is not supported)", v1)
+		spec, err := diskSpecForExtend(dss, ds, name)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		if _, err := extendVmfsDatastore(ctx, dss, ds, *spec); err != nil {
+			return err
 		}
 	}
 
-	// Now we basically reverse what we did above when we were checking for
-	// removed disks, and add any new disks that have been added.
-	for _, v1 := range new.([]interface{}) {
-		var found bool
-		for _, v2 := range old.([]interface{}) {
-			if v1.(string) == v2.(string) {
-				found = true
-			}
+	// Disks that are in state but no longer in config are being removed as
+	// extents. If the extent isn't the entire datastore, there's nothing sane
+	// to remove and we veto, whatever keep_on_remove is set to. Otherwise it
+	// depends on keep_on_remove: if it's set, we remove the datastore without
+	// touching the extent's VMFS partition table, since it's likely a shared
+	// SAN LUN the operator wants to keep intact for reuse. If it isn't set, we
+	// fall back to the extent's LUN being wiped via
+	// HostStorageSystem.RemoveScsiLun.
+	for _, v := range oldDisks.List() {
+		m := v.(map[string]interface{})
+		name := m["name"].(string)
+		if newDisks.Contains(v) {
+			continue
 		}
-		if !found {
-			// Add the disk
-			spec, err := diskSpecForExtend(dss, ds, v1.(string))
-			if err != nil {
-				return err
-			}
-			ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
-			defer cancel()
-			if _, err := extendVmfsDatastore(ctx, dss, ds, *spec); err != nil {
-				return err
-			}
+		if stillReferencedByName(newDisks, name) {
+			// Only keep_on_remove changed - nothing to do to the extent itself.
+			continue
+		}
+		keepOnRemove := m["keep_on_remove"].(bool)
+		if err := removeVmfsExtent(client, hsID, dss, ds, name, keepOnRemove); err != nil {
+			return err
 		}
 	}
 
@@ -285,6 +338,21 @@ func resourceVSphereVmfsDatastoreDelete(d *schema.ResourceData, meta interface{}
 		return fmt.Errorf("cannot find datastore: %s", err)
 	}
 
+	// Before we remove the datastore object, wipe the VMFS partition table on
+	// any extent that isn't flagged keep_on_remove, matching the behavior VM
+	// disks get when their own keep_on_remove is unset. Extents flagged
+	// keep_on_remove are left alone so a shared SAN LUN can be reused or
+	// re-imported elsewhere.
+	for _, v := range d.Get("disks").(*schema.Set).List() {
+		m := v.(map[string]interface{})
+		if m["keep_on_remove"].(bool) {
+			continue
+		}
+		if err := wipeVmfsExtent(client, hsID, m["name"].(string)); err != nil {
+			return fmt.Errorf("error wiping extent %q: %s", m["name"].(string), err)
+		}
+	}
+
 	// This is a race that more than likely will only come up during tests, but
 	// we still want to guard against it - when working with datastores that end
 	// up mounting across multiple hosts, removing the datastore will fail if
@@ -395,3 +463,144 @@ func resourceVSphereVmfsDatastoreImport(d *schema.ResourceData, meta interface{}
 
 	return []*schema.ResourceData{d}, nil
 }
+
+// resourceVSphereVmfsDatastoreMigrateState upgrades state for
+// vsphere_vmfs_datastore between schema versions.
+func resourceVSphereVmfsDatastoreMigrateState(v int, is *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
+	switch v {
+	case 0:
+		log.Printf("[DEBUG] resourceVSphereVmfsDatastoreMigrateState: migrating state from v0 to v1: %#v", is.Attributes)
+		return migrateVSphereVmfsDatastoreStateV0toV1(is)
+	default:
+		return is, fmt.Errorf("unexpected schema version: %d", v)
+	}
+}
+
+// migrateVSphereVmfsDatastoreStateV0toV1 converts the old list-style "disks"
+// attribute (a flat list of disk names) into the new set-style "disks"
+// attribute (a set of {name, keep_on_remove} objects), defaulting
+// keep_on_remove to false for every existing extent since the attribute
+// didn't exist prior to v1.
+func migrateVSphereVmfsDatastoreStateV0toV1(is *terraform.InstanceState) (*terraform.InstanceState, error) {
+	if is == nil || is.Attributes == nil {
+		return is, nil
+	}
+
+	var names []string
+	for k, v := range is.Attributes {
+		if k == "disks.#" {
+			continue
+		}
+		if strings.HasPrefix(k, "disks.") {
+			names = append(names, v)
+			delete(is.Attributes, k)
+		}
+	}
+	delete(is.Attributes, "disks.#")
+
+	r := &schema.Resource{Schema: vmfsDatastoreDiskSchema}
+	is.Attributes["disks.#"] = fmt.Sprintf("%d", len(names))
+	for _, name := range names {
+		m := map[string]interface{}{
+			"name":           name,
+			"keep_on_remove": false,
+		}
+		hash := schema.HashResource(r)(m)
+		is.Attributes[fmt.Sprintf("disks.%d.name", hash)] = name
+		is.Attributes[fmt.Sprintf("disks.%d.keep_on_remove", hash)] = "false"
+	}
+
+	log.Printf("[DEBUG] migrateVSphereVmfsDatastoreStateV0toV1: migrated state: %#v", is.Attributes)
+	return is, nil
+}
+
+// stillReferencedByName returns true if disks contains an entry with the
+// given extent name, regardless of what its keep_on_remove is set to. It's
+// used to tell a genuine extent add/remove apart from a keep_on_remove-only
+// change on an extent that's staying put.
+func stillReferencedByName(disks *schema.Set, name string) bool {
+	for _, v := range disks.List() {
+		if v.(map[string]interface{})["name"].(string) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// removeVmfsExtent detaches the extent identified by diskName from the
+// datastore. If keepOnRemove is true, the extent's VMFS partition table is
+// left intact so the underlying LUN can be reused or re-imported elsewhere.
+// If it's false and the extent backs the entire datastore, the LUN is
+// properly removed via HostStorageSystem.RemoveScsiLun. Removing a single
+// extent out of several is not supported, as VMFS does not support online
+// shrink of a datastore across less than all of its extents.
+func removeVmfsExtent(client *govmomi.Client, hsID string, dss *object.HostDatastoreSystem, ds *object.Datastore, diskName string, keepOnRemove bool) error {
+	props, err := datastoreProperties(ds)
+	if err != nil {
+		return fmt.Errorf("could not get properties for datastore: %s", err)
+	}
+	extents := props.Info.(*types.VmfsDatastoreInfo).Vmfs.Extent
+	if keepOnRemove {
+		if len(extents) > 1 {
+			return fmt.Errorf("cannot remove extent %q while keep_on_remove is set and other extents remain on datastore %q", diskName, ds.Name())
+		}
+		if err := removeDatastore(dss, ds); err != nil {
+			return fmt.Errorf("error detaching datastore %q while preserving extent %q: %s", ds.Name(), diskName, err)
+		}
+		return nil
+	}
+	if len(extents) > 1 {
+		return fmt.Errorf("disk %s found in state but not config (removal of a single extent from a multi-extent datastore is not supported)", diskName)
+	}
+	return wipeVmfsExtent(client, hsID, diskName)
+}
+
+// wipeVmfsExtent removes the LUN backing diskName via
+// HostStorageSystem.RemoveScsiLun, which unformats it, wiping the VMFS
+// partition table.
+func wipeVmfsExtent(client *govmomi.Client, hsID string, diskName string) error {
+	hss, err := hostStorageSystemFromHostSystemID(client, hsID)
+	if err != nil {
+		return fmt.Errorf("error loading host storage system: %s", err)
+	}
+	lun, err := scsiLunFromCanonicalName(hss, diskName)
+	if err != nil {
+		return fmt.Errorf("error finding disk %q: %s", diskName, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if err := hss.RemoveScsiLun(ctx, lun); err != nil {
+		return fmt.Errorf("error removing disk %q: %s", diskName, err)
+	}
+	return nil
+}
+
+// hostStorageSystemFromHostSystemID returns the HostStorageSystem for the
+// host with the given managed object ID.
+func hostStorageSystemFromHostSystemID(client *govmomi.Client, hostID string) (*object.HostStorageSystem, error) {
+	hs := object.NewHostSystem(client.Client, types.ManagedObjectReference{Type: "HostSystem", Value: hostID})
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	var mhs mo.HostSystem
+	if err := hs.Properties(ctx, hs.Reference(), []string{"configManager.storageSystem"}, &mhs); err != nil {
+		return nil, err
+	}
+	return object.NewHostStorageSystem(client.Client, *mhs.ConfigManager.StorageSystem), nil
+}
+
+// scsiLunFromCanonicalName locates the ScsiLun backing canonicalName on the
+// given HostStorageSystem.
+func scsiLunFromCanonicalName(hss *object.HostStorageSystem, canonicalName string) (types.ScsiLun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	var mhss mo.HostStorageSystem
+	if err := hss.Properties(ctx, hss.Reference(), []string{"storageDeviceInfo.scsiLun"}, &mhss); err != nil {
+		return types.ScsiLun{}, err
+	}
+	for _, l := range mhss.StorageDeviceInfo.ScsiLun {
+		if l.GetScsiLun().CanonicalName == canonicalName {
+			return *l.GetScsiLun(), nil
+		}
+	}
+	return types.ScsiLun{}, fmt.Errorf("could not find disk %q", canonicalName)
+}
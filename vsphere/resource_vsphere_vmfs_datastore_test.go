@@ -0,0 +1,73 @@
+package vsphere
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestStillReferencedByName(t *testing.T) {
+	r := &schema.Resource{Schema: vmfsDatastoreDiskSchema}
+	disks := schema.NewSet(schema.HashResource(r), []interface{}{
+		map[string]interface{}{"name": "disk1", "keep_on_remove": false},
+		map[string]interface{}{"name": "disk2", "keep_on_remove": true},
+	})
+
+	if !stillReferencedByName(disks, "disk1") {
+		t.Fatal("expected disk1 to be referenced")
+	}
+	if !stillReferencedByName(disks, "disk2") {
+		t.Fatal("expected disk2 to be referenced")
+	}
+	if stillReferencedByName(disks, "disk3") {
+		t.Fatal("expected disk3 to not be referenced")
+	}
+}
+
+func TestMigrateVSphereVmfsDatastoreStateV0toV1(t *testing.T) {
+	is := &terraform.InstanceState{
+		ID: "datastore-1",
+		Attributes: map[string]string{
+			"name":    "test-datastore",
+			"disks.#": "2",
+			"disks.0": "disk1",
+			"disks.1": "disk2",
+		},
+	}
+
+	migrated, err := migrateVSphereVmfsDatastoreStateV0toV1(is)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if migrated.Attributes["disks.#"] != "2" {
+		t.Fatalf("expected disks.# to be 2, got %q", migrated.Attributes["disks.#"])
+	}
+	if _, ok := migrated.Attributes["disks.0"]; ok {
+		t.Fatal("expected old disks.0 attribute to be removed")
+	}
+	if _, ok := migrated.Attributes["disks.1"]; ok {
+		t.Fatal("expected old disks.1 attribute to be removed")
+	}
+
+	var names []string
+	for k, v := range migrated.Attributes {
+		if k == "name" {
+			continue
+		}
+		if v == "disk1" || v == "disk2" {
+			names = append(names, v)
+		}
+	}
+	if len(names) != 2 {
+		t.Fatalf("expected 2 migrated disk names, got %d: %v", len(names), names)
+	}
+}
+
+func TestResourceVSphereVmfsDatastoreMigrateStateUnknownVersion(t *testing.T) {
+	is := &terraform.InstanceState{Attributes: map[string]string{}}
+	if _, err := resourceVSphereVmfsDatastoreMigrateState(1, is, nil); err == nil {
+		t.Fatal("expected an error for an unexpected schema version")
+	}
+}